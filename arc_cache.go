@@ -0,0 +1,177 @@
+package blockstore
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	arc "github.com/hashicorp/golang-lru/arc"
+)
+
+// arcCached wraps a Blockstore with an adaptive replacement cache that
+// memoizes recent Has/GetSize results, including negative ones, so a hot
+// key that's repeatedly probed (or repeatedly confirmed absent) doesn't
+// re-hit bs on every call.
+type arcCached struct {
+	bs    Blockstore
+	cache *arc.ARCCache
+
+	viewer Viewer
+
+	hits, misses uint64
+}
+
+var _ Blockstore = (*arcCached)(nil)
+
+// ARCCacheStats reports NewARCCached's Prometheus-style counters.
+type ARCCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// NewARCCached wraps bs with an adaptive replacement cache holding up to
+// size entries.
+func NewARCCached(bs Blockstore, size int) (*arcCached, error) {
+	c, err := arc.NewARC(size)
+	if err != nil {
+		return nil, err
+	}
+	ac := &arcCached{bs: bs, cache: c}
+	if v, ok := bs.(Viewer); ok {
+		ac.viewer = v
+	}
+	return ac, nil
+}
+
+// cacheKey uses the hash rather than the full CID so that raw and dag-pb
+// CIDs over the same bytes share a cache entry.
+func cacheKey(k cid.Cid) string {
+	return string(k.Hash())
+}
+
+func (b *arcCached) Has(ctx context.Context, k cid.Cid) (bool, error) {
+	key := cacheKey(k)
+	if v, ok := b.cache.Get(key); ok {
+		atomic.AddUint64(&b.hits, 1)
+		// A cached int means GetSize already confirmed presence (and
+		// learned the size); only an explicit bool(false) means absent.
+		if has, isBool := v.(bool); isBool {
+			return has, nil
+		}
+		return true, nil
+	}
+	atomic.AddUint64(&b.misses, 1)
+	has, err := b.bs.Has(ctx, k)
+	if err == nil {
+		b.cache.Add(key, has)
+	}
+	return has, err
+}
+
+func (b *arcCached) GetSize(ctx context.Context, k cid.Cid) (int, error) {
+	key := cacheKey(k)
+	if v, ok := b.cache.Get(key); ok {
+		switch sz := v.(type) {
+		case int:
+			atomic.AddUint64(&b.hits, 1)
+			return sz, nil
+		case bool:
+			if !sz {
+				atomic.AddUint64(&b.hits, 1)
+				return -1, ipld.ErrNotFound{}
+			}
+			// Known to exist (cached by a prior Has) but its size isn't
+			// cached yet; fall through to a real GetSize below without
+			// double-counting this lookup as both a hit and a miss.
+		}
+	}
+	atomic.AddUint64(&b.misses, 1)
+	sz, err := b.bs.GetSize(ctx, k)
+	switch {
+	case err == nil:
+		b.cache.Add(key, sz)
+	case errors.Is(err, ipld.ErrNotFound{}):
+		b.cache.Add(key, false)
+	}
+	return sz, err
+}
+
+func (b *arcCached) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
+	// Block contents are too large to memoize profitably here; only
+	// presence/size are cached. A confirmed absence still short-circuits.
+	key := cacheKey(k)
+	if v, ok := b.cache.Get(key); ok {
+		if has, isBool := v.(bool); isBool && !has {
+			atomic.AddUint64(&b.hits, 1)
+			return nil, ipld.ErrNotFound{}
+		}
+	}
+	atomic.AddUint64(&b.misses, 1)
+	blk, err := b.bs.Get(ctx, k)
+	switch {
+	case err == nil:
+		b.cache.Add(key, len(blk.RawData()))
+	case errors.Is(err, ipld.ErrNotFound{}):
+		b.cache.Add(key, false)
+	}
+	return blk, err
+}
+
+func (b *arcCached) View(ctx context.Context, k cid.Cid, callback func([]byte) error) error {
+	if b.viewer == nil {
+		blk, err := b.Get(ctx, k)
+		if err != nil {
+			return err
+		}
+		return callback(blk.RawData())
+	}
+	key := cacheKey(k)
+	if v, ok := b.cache.Get(key); ok {
+		if has, isBool := v.(bool); isBool && !has {
+			return ipld.ErrNotFound{}
+		}
+	}
+	return b.viewer.View(ctx, k, callback)
+}
+
+func (b *arcCached) DeleteBlock(ctx context.Context, k cid.Cid) error {
+	b.cache.Remove(cacheKey(k))
+	return b.bs.DeleteBlock(ctx, k)
+}
+
+func (b *arcCached) Put(ctx context.Context, bl blocks.Block) error {
+	if err := b.bs.Put(ctx, bl); err != nil {
+		return err
+	}
+	b.cache.Add(cacheKey(bl.Cid()), true)
+	return nil
+}
+
+func (b *arcCached) PutMany(ctx context.Context, bs []blocks.Block) error {
+	if err := b.bs.PutMany(ctx, bs); err != nil {
+		return err
+	}
+	for _, bl := range bs {
+		b.cache.Add(cacheKey(bl.Cid()), true)
+	}
+	return nil
+}
+
+func (b *arcCached) HashOnRead(enabled bool) {
+	b.bs.HashOnRead(enabled)
+}
+
+func (b *arcCached) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	return b.bs.AllKeysChan(ctx)
+}
+
+// Stats returns a snapshot of the cache's hit/miss counters.
+func (b *arcCached) Stats() ARCCacheStats {
+	return ARCCacheStats{
+		Hits:   atomic.LoadUint64(&b.hits),
+		Misses: atomic.LoadUint64(&b.misses),
+	}
+}
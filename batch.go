@@ -0,0 +1,160 @@
+package blockstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// BlockOrError is one result of a GetMany call: either Block is set, or
+// Err is.
+type BlockOrError struct {
+	Cid   cid.Cid
+	Block blocks.Block
+	Err   error
+}
+
+// BatchBlockstore is an optional Blockstore extension for stores that can
+// serve many CIDs more efficiently as a batch than as a serial loop of
+// Get/Has calls, e.g. by fanning out remote fetches in parallel instead of
+// one at a time.
+type BatchBlockstore interface {
+	// GetMany streams a BlockOrError per requested CID, in no particular
+	// order, closing the channel once every CID has been resolved or ctx
+	// is done.
+	GetMany(ctx context.Context, cids []cid.Cid) <-chan BlockOrError
+	// HasMany reports, for each CID in cids, whether it is present. The
+	// returned slice is the same length as cids and in the same order.
+	HasMany(ctx context.Context, cids []cid.Cid) ([]bool, error)
+}
+
+var _ BatchBlockstore = (*idstore)(nil)
+
+const batchWorkers = 16
+
+// GetMany resolves identity CIDs synchronously, checks the rest against
+// the local store in a single pass, and fans the remaining misses out to
+// the remote-fetch path (b.bs, which already coalesces concurrent misses
+// for the same CID via singleflight) with a bounded worker pool, streaming
+// each result back as it arrives.
+func (b *idstore) GetMany(ctx context.Context, cids []cid.Cid) <-chan BlockOrError {
+	out := make(chan BlockOrError, len(cids))
+	go func() {
+		defer close(out)
+
+		send := func(r BlockOrError) bool {
+			select {
+			case out <- r:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var candidates []cid.Cid
+		for _, c := range cids {
+			isId, bdata := extractContents(c)
+			if !isId {
+				candidates = append(candidates, c)
+				continue
+			}
+			blk, err := blocks.NewBlockWithCid(bdata, c)
+			if !send(BlockOrError{Cid: c, Block: blk, Err: err}) {
+				return
+			}
+		}
+
+		var misses []cid.Cid
+		for _, c := range candidates {
+			blk, err := b.local.Get(ctx, c)
+			switch {
+			case err == nil:
+				if !send(BlockOrError{Cid: c, Block: blk}) {
+					return
+				}
+			case errors.Is(err, ipld.ErrNotFound{}):
+				misses = append(misses, c)
+			default:
+				if !send(BlockOrError{Cid: c, Err: err}) {
+					return
+				}
+			}
+		}
+		if len(misses) == 0 {
+			return
+		}
+
+		jobs := make(chan cid.Cid)
+		var wg sync.WaitGroup
+		for i := 0; i < batchWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for c := range jobs {
+					blk, err := b.bs.Get(ctx, c)
+					if !send(BlockOrError{Cid: c, Block: blk, Err: err}) {
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			defer close(jobs)
+			for _, c := range misses {
+				select {
+				case jobs <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		wg.Wait()
+	}()
+	return out
+}
+
+// HasMany checks every CID concurrently, bounded by batchWorkers, and
+// returns a bool per CID in the same order as cids.
+func (b *idstore) HasMany(ctx context.Context, cids []cid.Cid) ([]bool, error) {
+	results := make([]bool, len(cids))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for i := 0; i < batchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				ok, err := b.Has(ctx, cids[idx])
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					continue
+				}
+				results[idx] = ok
+			}
+		}()
+	}
+	for i := range cids {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			return results, ctx.Err()
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return results, firstErr
+}
@@ -0,0 +1,99 @@
+package blockstore
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func TestIdStoreHasManyPreservesOrder(t *testing.T) {
+	ctx := context.Background()
+	bs := newFakeBlockstore()
+
+	present := make([]blocks.Block, 5)
+	for i := range present {
+		present[i] = blocks.NewBlock([]byte{byte(i)})
+		if err := bs.Put(ctx, present[i]); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	absent := blocks.NewBlock([]byte("absent"))
+
+	ids := NewIdStore(bs).(BatchBlockstore)
+
+	cids := []cid.Cid{
+		present[0].Cid(),
+		absent.Cid(),
+		present[1].Cid(),
+		present[2].Cid(),
+		absent.Cid(),
+		present[3].Cid(),
+		present[4].Cid(),
+	}
+	want := []bool{true, false, true, true, false, true, true}
+
+	got, err := ids.HasMany(ctx, cids)
+	if err != nil {
+		t.Fatalf("hasmany: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cids[%d] = %s: got %v, want %v", i, cids[i], got[i], want[i])
+		}
+	}
+}
+
+func TestIdStoreGetManyResolvesIdentityLocalAndRemote(t *testing.T) {
+	ctx := context.Background()
+	bs := newFakeBlockstore()
+
+	local := blocks.NewBlock([]byte("local"))
+	if err := bs.Put(ctx, local); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	remoteData := []byte("remote")
+	remoteBlk := blocks.NewBlock(remoteData)
+	f := &countingFetcher{data: remoteData}
+
+	store := NewIdStoreWithFetchers(bs, FetcherOptions{}, f)
+	ids := store.(BatchBlockstore)
+
+	idHash, err := mh.Encode([]byte("inline"), mh.IDENTITY)
+	if err != nil {
+		t.Fatalf("identity hash: %v", err)
+	}
+	idCid := cid.NewCidV1(cid.Raw, idHash)
+
+	want := map[cid.Cid][]byte{
+		idCid:           []byte("inline"),
+		local.Cid():     local.RawData(),
+		remoteBlk.Cid(): remoteData,
+	}
+
+	cids := []cid.Cid{idCid, local.Cid(), remoteBlk.Cid()}
+	got := make(map[cid.Cid][]byte, len(cids))
+	for r := range ids.GetMany(ctx, cids) {
+		if r.Err != nil {
+			t.Fatalf("get %s: %v", r.Cid, r.Err)
+		}
+		got[r.Cid] = r.Block.RawData()
+	}
+
+	for c, data := range want {
+		gotData, ok := got[c]
+		if !ok {
+			t.Errorf("missing result for %s", c)
+			continue
+		}
+		if string(gotData) != string(data) {
+			t.Errorf("%s: got %q, want %q", c, gotData, data)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package blockstore
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	bloom "github.com/ipfs/bbloom"
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// bloomCached wraps a Blockstore with a bloom filter populated from
+// AllKeysChan at startup and kept up to date on every Put, so Has and the
+// miss-path of Get can answer "definitely not present" without touching
+// bs, which matters most when bs is something like idstore with a remote
+// fallback behind it. A bloom filter never produces false negatives, so a
+// "no" from the filter is always trustworthy; a "maybe" still has to go to
+// bs. The filter is never shrunk on delete, so its false-positive rate
+// only grows over the life of the store.
+type bloomCached struct {
+	bs     Blockstore
+	filter *bloom.Bloom
+
+	// active flips to 1 once the initial AllKeysChan fill completes; until
+	// then the filter can't be trusted for negatives, so we fall through.
+	active int32
+
+	hits, misses, falsePos uint64
+}
+
+var _ Blockstore = (*bloomCached)(nil)
+
+// BloomCacheStats reports NewBloomCached's Prometheus-style counters.
+type BloomCacheStats struct {
+	Hits           uint64
+	Misses         uint64
+	FalsePositives uint64
+}
+
+// NewBloomCached wraps bs with a bloom filter sized for `size` entries
+// using `hashes` hash functions. The filter is filled asynchronously from
+// bs.AllKeysChan; until the fill completes, Has/Get fall straight through
+// to bs.
+func NewBloomCached(ctx context.Context, bs Blockstore, size int, hashes int) (*bloomCached, error) {
+	f, err := bloom.New(float64(size), float64(hashes))
+	if err != nil {
+		return nil, err
+	}
+	bc := &bloomCached{bs: bs, filter: f}
+	go bc.fill(ctx)
+	return bc, nil
+}
+
+func (b *bloomCached) fill(ctx context.Context) {
+	ch, err := b.bs.AllKeysChan(ctx)
+	if err != nil {
+		logger.Warn("bloom cache: initial fill failed to list keys : ", err.Error())
+		return
+	}
+	for c := range ch {
+		b.filter.AddTS(c.Hash())
+	}
+	atomic.StoreInt32(&b.active, 1)
+}
+
+// mightHave reports whether k could be in bs. false is definitive; true
+// means "ask bs" (including while the initial fill is still running).
+func (b *bloomCached) mightHave(k cid.Cid) bool {
+	if atomic.LoadInt32(&b.active) == 0 {
+		return true
+	}
+	return b.filter.HasTS(k.Hash())
+}
+
+func (b *bloomCached) Has(ctx context.Context, k cid.Cid) (bool, error) {
+	if !b.mightHave(k) {
+		atomic.AddUint64(&b.misses, 1)
+		return false, nil
+	}
+	atomic.AddUint64(&b.hits, 1)
+	ok, err := b.bs.Has(ctx, k)
+	if err == nil && !ok && atomic.LoadInt32(&b.active) == 1 {
+		atomic.AddUint64(&b.falsePos, 1)
+	}
+	return ok, err
+}
+
+func (b *bloomCached) GetSize(ctx context.Context, k cid.Cid) (int, error) {
+	if !b.mightHave(k) {
+		atomic.AddUint64(&b.misses, 1)
+		return -1, ipld.ErrNotFound{}
+	}
+	atomic.AddUint64(&b.hits, 1)
+	sz, err := b.bs.GetSize(ctx, k)
+	if err != nil && errors.Is(err, ipld.ErrNotFound{}) && atomic.LoadInt32(&b.active) == 1 {
+		atomic.AddUint64(&b.falsePos, 1)
+	}
+	return sz, err
+}
+
+func (b *bloomCached) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
+	if !b.mightHave(k) {
+		atomic.AddUint64(&b.misses, 1)
+		return nil, ipld.ErrNotFound{}
+	}
+	atomic.AddUint64(&b.hits, 1)
+	blk, err := b.bs.Get(ctx, k)
+	if err != nil && errors.Is(err, ipld.ErrNotFound{}) && atomic.LoadInt32(&b.active) == 1 {
+		atomic.AddUint64(&b.falsePos, 1)
+	}
+	return blk, err
+}
+
+func (b *bloomCached) DeleteBlock(ctx context.Context, k cid.Cid) error {
+	// The filter is never told about deletes: bbloom can't remove a
+	// member, so k keeps producing a "maybe" until the filter is rebuilt.
+	return b.bs.DeleteBlock(ctx, k)
+}
+
+func (b *bloomCached) Put(ctx context.Context, bl blocks.Block) error {
+	if err := b.bs.Put(ctx, bl); err != nil {
+		return err
+	}
+	b.filter.AddTS(bl.Cid().Hash())
+	return nil
+}
+
+func (b *bloomCached) PutMany(ctx context.Context, bs []blocks.Block) error {
+	if err := b.bs.PutMany(ctx, bs); err != nil {
+		return err
+	}
+	for _, bl := range bs {
+		b.filter.AddTS(bl.Cid().Hash())
+	}
+	return nil
+}
+
+func (b *bloomCached) HashOnRead(enabled bool) {
+	b.bs.HashOnRead(enabled)
+}
+
+func (b *bloomCached) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	return b.bs.AllKeysChan(ctx)
+}
+
+// Stats returns a snapshot of the cache's hit/miss/false-positive counters.
+func (b *bloomCached) Stats() BloomCacheStats {
+	return BloomCacheStats{
+		Hits:           atomic.LoadUint64(&b.hits),
+		Misses:         atomic.LoadUint64(&b.misses),
+		FalsePositives: atomic.LoadUint64(&b.falsePos),
+	}
+}
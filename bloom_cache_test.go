@@ -0,0 +1,76 @@
+package blockstore
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+)
+
+func TestBloomCachedRejectsDefiniteNonMembers(t *testing.T) {
+	ctx := context.Background()
+	bs := newFakeBlockstore()
+
+	present := blocks.NewBlock([]byte("present"))
+	if err := bs.Put(ctx, present); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	bc, err := NewBloomCached(ctx, bs, 1000, 7)
+	if err != nil {
+		t.Fatalf("new bloom cached: %v", err)
+	}
+	waitUntil(t, func() bool { return atomic.LoadInt32(&bc.active) == 1 })
+
+	absent := blocks.NewBlock([]byte("absent"))
+	ok, err := bc.Has(ctx, absent.Cid())
+	if err != nil {
+		t.Fatalf("has: %v", err)
+	}
+	if ok {
+		t.Errorf("expected Has to report false for a block never stored")
+	}
+
+	ok, err = bc.Has(ctx, present.Cid())
+	if err != nil {
+		t.Fatalf("has: %v", err)
+	}
+	if !ok {
+		t.Errorf("expected Has to report true for a block that was stored before the filter filled")
+	}
+}
+
+func TestBloomCachedPutIsVisibleImmediately(t *testing.T) {
+	ctx := context.Background()
+	bs := newFakeBlockstore()
+	bc, err := NewBloomCached(ctx, bs, 1000, 7)
+	if err != nil {
+		t.Fatalf("new bloom cached: %v", err)
+	}
+	waitUntil(t, func() bool { return atomic.LoadInt32(&bc.active) == 1 })
+
+	blk := blocks.NewBlock([]byte("fresh"))
+	if err := bc.Put(ctx, blk); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	ok, err := bc.Has(ctx, blk.Cid())
+	if err != nil {
+		t.Fatalf("has: %v", err)
+	}
+	if !ok {
+		t.Errorf("expected a block just Put through the bloom cache to be reported present")
+	}
+}
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
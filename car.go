@@ -0,0 +1,245 @@
+package blockstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	cbornode "github.com/ipfs/go-ipld-cbor"
+	car "github.com/ipld/go-car/v2"
+	varint "github.com/multiformats/go-varint"
+)
+
+// ExportOptions controls how ExportCAR walks and encodes a DAG.
+type ExportOptions struct {
+	// Version selects the CAR format: 1 (default) or 2.
+	Version int
+	// Index requests a CARv2 index; ignored unless Version == 2. Index
+	// generation is not implemented yet: ExportCAR returns an error if
+	// Index is true, rather than silently writing an unindexed file.
+	Index bool
+	// Selector, when non-nil, restricts the walk: a CID is only visited
+	// (and its own links followed) when Selector returns true. Nil walks
+	// the full DAG reachable from roots.
+	Selector func(c cid.Cid) bool
+}
+
+// ImportCAR streams every block in the CAR read from r into bs, batching
+// writes through PutMany, and returns the CAR's declared roots.
+func ImportCAR(ctx context.Context, bs Blockstore, r io.Reader) ([]cid.Cid, error) {
+	cr, err := car.NewBlockReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("car: read header: %w", err)
+	}
+
+	const batchSize = 256
+	batch := make([]blocks.Block, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := bs.PutMany(ctx, batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		blk, err := cr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("car: read block: %w", err)
+		}
+		batch = append(batch, blk)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return cr.Roots, nil
+}
+
+// ExportCAR walks the DAG reachable from roots via bs.Get and writes it as
+// a CAR to w. Identity-hash CIDs are never emitted as blocks: their
+// contents are inline in the CID itself, so a reader reconstructs them
+// without needing a stored entry.
+func ExportCAR(ctx context.Context, bs Blockstore, roots []cid.Cid, w io.Writer, opts ExportOptions) error {
+	version := opts.Version
+	if version == 0 {
+		version = 1
+	}
+	if version != 1 && version != 2 {
+		return fmt.Errorf("car: unsupported version %d", version)
+	}
+
+	toEmit, err := walkDAG(ctx, bs, roots, opts.Selector)
+	if err != nil {
+		return err
+	}
+
+	if version == 1 {
+		return writeCARv1(ctx, bs, roots, toEmit, w)
+	}
+	return writeCARv2(ctx, bs, roots, toEmit, w, opts.Index)
+}
+
+// walkDAG does a breadth-first walk from roots, following links extracted
+// from each fetched block, skipping identity CIDs (nothing to fetch or
+// emit for them) and anything Selector rejects.
+func walkDAG(ctx context.Context, bs Blockstore, roots []cid.Cid, selector func(cid.Cid) bool) ([]cid.Cid, error) {
+	visited := make(map[cid.Cid]struct{}, len(roots))
+	queue := append([]cid.Cid(nil), roots...)
+	var toEmit []cid.Cid
+
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[c]; ok {
+			continue
+		}
+		visited[c] = struct{}{}
+		if selector != nil && !selector(c) {
+			continue
+		}
+
+		if isId, bdata := extractContents(c); isId {
+			// The identity CID's contents are inline, never emitted as a
+			// block of their own, but they can still be a DAG-CBOR/dag-pb
+			// node with real links, so those still need discovering.
+			blk, err := blocks.NewBlockWithCid(bdata, c)
+			if err != nil {
+				return nil, fmt.Errorf("car: rebuild identity block %s: %w", c, err)
+			}
+			links, err := blockLinks(blk)
+			if err != nil {
+				return nil, fmt.Errorf("car: decode links of %s: %w", c, err)
+			}
+			queue = append(queue, links...)
+			continue
+		}
+		toEmit = append(toEmit, c)
+
+		blk, err := bs.Get(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("car: get %s: %w", c, err)
+		}
+		links, err := blockLinks(blk)
+		if err != nil {
+			return nil, fmt.Errorf("car: decode links of %s: %w", c, err)
+		}
+		queue = append(queue, links...)
+	}
+	return toEmit, nil
+}
+
+func blockLinks(blk blocks.Block) ([]cid.Cid, error) {
+	nd, err := ipld.Decode(blk)
+	if err != nil {
+		return nil, err
+	}
+	links := nd.Links()
+	out := make([]cid.Cid, len(links))
+	for i, l := range links {
+		out[i] = l.Cid
+	}
+	return out, nil
+}
+
+// writeCARv1 streams a CARv1 directly to w: a DAG-CBOR header followed by
+// varint-length-prefixed (CID, data) sections, one per block in toEmit.
+func writeCARv1(ctx context.Context, bs Blockstore, roots, toEmit []cid.Cid, w io.Writer) error {
+	header, err := cbornode.DumpObject(struct {
+		Roots   []cid.Cid
+		Version uint64
+	}{Roots: roots, Version: 1})
+	if err != nil {
+		return fmt.Errorf("car: encode header: %w", err)
+	}
+	if err := ldWrite(w, header); err != nil {
+		return fmt.Errorf("car: write header: %w", err)
+	}
+
+	for _, c := range toEmit {
+		blk, err := bs.Get(ctx, c)
+		if err != nil {
+			return fmt.Errorf("car: get %s: %w", c, err)
+		}
+		if err := ldWrite(w, c.Bytes(), blk.RawData()); err != nil {
+			return fmt.Errorf("car: write block %s: %w", c, err)
+		}
+	}
+	return nil
+}
+
+// ldWrite writes a varint-length-prefixed concatenation of segs, matching
+// the CARv1 "length-delimited" framing.
+func ldWrite(w io.Writer, segs ...[]byte) error {
+	var sum uint64
+	for _, s := range segs {
+		sum += uint64(len(s))
+	}
+	buf := make([]byte, varint.UvarintSize(sum))
+	n := varint.PutUvarint(buf, sum)
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	for _, s := range segs {
+		if _, err := w.Write(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// carV2Pragma is the fixed 11-byte CARv2 pragma: the dag-cbor encoding of
+// {"version": 2}.
+var carV2Pragma = []byte{0x0a, 0xa1, 0x67, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x02}
+
+const (
+	carV2PragmaSize = len(carV2Pragma)
+	carV2HeaderSize = 40 // 16-byte characteristics + 3 uint64 LE fields
+)
+
+// writeCARv2 wraps a CARv1 payload (built with writeCARv1) in the fixed
+// CARv2 pragma and header, writing IndexOffset as 0 to mark the file as
+// having no index. Index generation itself is not implemented: callers
+// asking for one get an explicit error rather than a file that silently
+// has none.
+func writeCARv2(ctx context.Context, bs Blockstore, roots, toEmit []cid.Cid, w io.Writer, withIndex bool) error {
+	if withIndex {
+		return fmt.Errorf("car: CARv2 index generation is not implemented; export with Index: false")
+	}
+
+	var payload bytes.Buffer
+	if err := writeCARv1(ctx, bs, roots, toEmit, &payload); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(carV2Pragma); err != nil {
+		return fmt.Errorf("car: write v2 pragma: %w", err)
+	}
+
+	header := make([]byte, carV2HeaderSize)
+	binary.LittleEndian.PutUint64(header[16:24], uint64(carV2PragmaSize+carV2HeaderSize)) // DataOffset
+	binary.LittleEndian.PutUint64(header[24:32], uint64(payload.Len()))                   // DataSize
+	// header[32:40] (IndexOffset) stays 0: no index.
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("car: write v2 header: %w", err)
+	}
+
+	_, err := w.Write(payload.Bytes())
+	return err
+}
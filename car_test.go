@@ -0,0 +1,64 @@
+package blockstore
+
+import (
+	"context"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	cbornode "github.com/ipfs/go-ipld-cbor"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// TestWalkDAGFollowsIdentityLinks verifies that an identity-hash CID that
+// is itself a node with links still has those links discovered, even
+// though the identity CID is never fetched from the store and never
+// emitted.
+func TestWalkDAGFollowsIdentityLinks(t *testing.T) {
+	ctx := context.Background()
+	bs := newFakeBlockstore()
+
+	leaf, err := cbornode.WrapObject("leaf", mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatalf("wrap leaf: %v", err)
+	}
+	if err := bs.Put(ctx, leaf); err != nil {
+		t.Fatalf("put leaf: %v", err)
+	}
+
+	idNode, err := cbornode.WrapObject(map[string]interface{}{"leaf": leaf.Cid()}, mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatalf("wrap identity node: %v", err)
+	}
+	idHash, err := mh.Encode(idNode.RawData(), mh.IDENTITY)
+	if err != nil {
+		t.Fatalf("encode identity hash: %v", err)
+	}
+	idCid := cid.NewCidV1(cid.DagCBOR, idHash)
+
+	root, err := cbornode.WrapObject(map[string]interface{}{"id": idCid}, mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatalf("wrap root: %v", err)
+	}
+	if err := bs.Put(ctx, root); err != nil {
+		t.Fatalf("put root: %v", err)
+	}
+
+	toEmit, err := walkDAG(ctx, bs, []cid.Cid{root.Cid()}, nil)
+	if err != nil {
+		t.Fatalf("walkDAG: %v", err)
+	}
+
+	seen := make(map[cid.Cid]bool, len(toEmit))
+	for _, c := range toEmit {
+		seen[c] = true
+	}
+	if !seen[root.Cid()] {
+		t.Errorf("expected root %s in toEmit", root.Cid())
+	}
+	if !seen[leaf.Cid()] {
+		t.Errorf("expected leaf %s reachable only through the identity node to be in toEmit", leaf.Cid())
+	}
+	if seen[idCid] {
+		t.Errorf("identity CID %s should never be emitted as a block", idCid)
+	}
+}
@@ -2,37 +2,61 @@ package blockstore
 
 import (
 	"context"
-	"errors"
 	blocks "github.com/ipfs/go-block-format"
 	cid "github.com/ipfs/go-cid"
-	ipld "github.com/ipfs/go-ipld-format"
 	mh "github.com/multiformats/go-multihash"
-	"github.com/timtide/titan-client/util"
 	"io"
 )
 
 // idstore wraps a BlockStore to add support for identity hashes
 type idstore struct {
 	bs     Blockstore
+	mhbs   MHBlockstore
 	viewer Viewer
-	ds     util.DataService
+
+	// local is bs's storage without the remote-fetch fallback layer, used
+	// by GetMany to tell a local miss apart from one that needs a remote
+	// fetch without paying for that fetch itself.
+	local Blockstore
 }
 
 var _ Blockstore = (*idstore)(nil)
 var _ Viewer = (*idstore)(nil)
 var _ io.Closer = (*idstore)(nil)
 
+// NewIdStore routes bs through a multihash-keyed layer, so that raw and
+// dag-pb CIDs (or any two codecs) pointing at identical bytes share a
+// single stored copy, and through a RemoteFetchBlockstore that falls
+// through to Titan on a local miss. Use NewIdStoreWithFetchers to swap in
+// different fetchers or tune their retrieval policy.
 func NewIdStore(bs Blockstore) Blockstore {
+	return NewIdStoreWithFetchers(bs, FetcherOptions{}, NewTitanFetcher())
+}
+
+// NewIdStoreWithFetchers is NewIdStore with control over which
+// RemoteFetchers are tried on a local miss, and on what policy (see
+// FetcherOptions). Fetchers are tried in the order given.
+func NewIdStoreWithFetchers(bs Blockstore, opts FetcherOptions, fetchers ...RemoteFetcher) Blockstore {
+	withRemote := NewRemoteFetchBlockstore(bs, opts, fetchers...)
+	mhbs := NewMHBlockstore(withRemote)
+	backed := NewMHBacked(mhbs)
 	ids := &idstore{
-		bs: bs,
-		ds: util.NewDataService(),
+		bs:    backed,
+		mhbs:  mhbs,
+		local: NewMHBacked(NewMHBlockstore(bs)),
 	}
-	if v, ok := bs.(Viewer); ok {
+	if v, ok := backed.(Viewer); ok {
 		ids.viewer = v
 	}
 	return ids
 }
 
+// AllHashesChan is the multihash-keyed counterpart to AllKeysChan, exposing
+// the underlying store's keys without forcing a codec onto them.
+func (b *idstore) AllHashesChan(ctx context.Context) (<-chan mh.Multihash, error) {
+	return b.mhbs.AllHashesChan(ctx)
+}
+
 func extractContents(k cid.Cid) (bool, []byte) {
 	// Pre-check by calling Prefix(), this much faster than extracting the hash.
 	if k.Prefix().MhType != mh.IDENTITY {
@@ -90,28 +114,9 @@ func (b *idstore) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
 	if isId {
 		return blocks.NewBlockWithCid(bdata, k)
 	}
-	bb, err := b.bs.Get(ctx, k)
-	if err != nil && errors.Is(err, ipld.ErrNotFound{}) {
-		// titan
-		data, err := b.ds.GetDataFromTitanByCid(ctx, k)
-		if err != nil {
-			logger.Warn("from titan to get data fail : ", err.Error())
-			return nil, ipld.ErrNotFound{}
-		}
-		block, err := blocks.NewBlockWithCid(data, k)
-		if err != nil {
-			logger.Warn("New block with cid fail : ", err.Error())
-			return nil, ipld.ErrNotFound{}
-		}
-		logger.Debugf("got block from titan, cid[%s]", k.String())
-		err = b.bs.Put(ctx, block)
-		if err != nil {
-			logger.Warn("put to localhost fail : ", err.Error())
-		}
-		return block, nil
-	}
-	logger.Debugf("got block from localhost, cid[%s]", k.String())
-	return bb, err
+	// The remote-fetch fallback (Titan by default) lives in b.bs now; see
+	// NewIdStoreWithFetchers.
+	return b.bs.Get(ctx, k)
 }
 
 func (b *idstore) Put(ctx context.Context, bl blocks.Block) error {
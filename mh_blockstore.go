@@ -0,0 +1,219 @@
+package blockstore
+
+import (
+	"context"
+	"io"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// MHBlockstore is the Blockstore family keyed by multihash rather than by
+// full CID. Because the codec byte of a CID carries no information about
+// the bytes themselves, two CIDs built from different codecs (e.g. raw and
+// dag-pb) but the same hash address the same content; storing by multihash
+// lets that content be kept once instead of once per codec.
+type MHBlockstore interface {
+	DeleteBlock(ctx context.Context, h mh.Multihash) error
+	Has(ctx context.Context, h mh.Multihash) (bool, error)
+	Get(ctx context.Context, h mh.Multihash) ([]byte, error)
+	GetSize(ctx context.Context, h mh.Multihash) (int, error)
+	Put(ctx context.Context, h mh.Multihash, data []byte) error
+	PutMany(ctx context.Context, blks []MHBlock) error
+	AllHashesChan(ctx context.Context) (<-chan mh.Multihash, error)
+}
+
+// MHBlock pairs a multihash with the bytes it addresses, the MHBlockstore
+// analog of blocks.Block.
+type MHBlock struct {
+	Hash mh.Multihash
+	Data []byte
+}
+
+// mhAdapter implements MHBlockstore on top of an ordinary Blockstore by
+// addressing every multihash with a single canonical CID (raw codec,
+// CIDv1), so that whatever codec a caller originally used to store the
+// bytes, a lookup by hash always lands on the same underlying key.
+type mhAdapter struct {
+	bs Blockstore
+}
+
+var _ MHBlockstore = (*mhAdapter)(nil)
+
+// NewMHBlockstore wraps bs so it can be addressed by multihash instead of
+// by CID.
+func NewMHBlockstore(bs Blockstore) MHBlockstore {
+	return &mhAdapter{bs: bs}
+}
+
+func canonicalCid(h mh.Multihash) cid.Cid {
+	return cid.NewCidV1(cid.Raw, h)
+}
+
+func (a *mhAdapter) DeleteBlock(ctx context.Context, h mh.Multihash) error {
+	return a.bs.DeleteBlock(ctx, canonicalCid(h))
+}
+
+func (a *mhAdapter) Has(ctx context.Context, h mh.Multihash) (bool, error) {
+	return a.bs.Has(ctx, canonicalCid(h))
+}
+
+func (a *mhAdapter) Get(ctx context.Context, h mh.Multihash) ([]byte, error) {
+	blk, err := a.bs.Get(ctx, canonicalCid(h))
+	if err != nil {
+		return nil, err
+	}
+	return blk.RawData(), nil
+}
+
+func (a *mhAdapter) GetSize(ctx context.Context, h mh.Multihash) (int, error) {
+	return a.bs.GetSize(ctx, canonicalCid(h))
+}
+
+func (a *mhAdapter) Put(ctx context.Context, h mh.Multihash, data []byte) error {
+	blk, err := blocks.NewBlockWithCid(data, canonicalCid(h))
+	if err != nil {
+		return err
+	}
+	return a.bs.Put(ctx, blk)
+}
+
+func (a *mhAdapter) PutMany(ctx context.Context, blks []MHBlock) error {
+	toPut := make([]blocks.Block, 0, len(blks))
+	for _, b := range blks {
+		blk, err := blocks.NewBlockWithCid(b.Data, canonicalCid(b.Hash))
+		if err != nil {
+			return err
+		}
+		toPut = append(toPut, blk)
+	}
+	return a.bs.PutMany(ctx, toPut)
+}
+
+func (a *mhAdapter) AllHashesChan(ctx context.Context) (<-chan mh.Multihash, error) {
+	ch, err := a.bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan mh.Multihash)
+	go func() {
+		defer close(out)
+		for c := range ch {
+			select {
+			case out <- c.Hash():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (a *mhAdapter) HashOnRead(enabled bool) {
+	a.bs.HashOnRead(enabled)
+}
+
+func (a *mhAdapter) Close() error {
+	if c, ok := a.bs.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// mhBacked implements Blockstore on top of an MHBlockstore, so callers that
+// only know how to speak CIDs (idstore, the Titan fetch path) can share a
+// single multihash-keyed store underneath. The CID codec never touches
+// storage: it is supplied by the caller on every call and paired back onto
+// the hash-addressed bytes on read.
+type mhBacked struct {
+	mbs MHBlockstore
+}
+
+var _ Blockstore = (*mhBacked)(nil)
+var _ Viewer = (*mhBacked)(nil)
+
+// NewMHBacked adapts mbs into a Blockstore.
+func NewMHBacked(mbs MHBlockstore) Blockstore {
+	return &mhBacked{mbs: mbs}
+}
+
+func (b *mhBacked) DeleteBlock(ctx context.Context, k cid.Cid) error {
+	return b.mbs.DeleteBlock(ctx, k.Hash())
+}
+
+func (b *mhBacked) Has(ctx context.Context, k cid.Cid) (bool, error) {
+	return b.mbs.Has(ctx, k.Hash())
+}
+
+func (b *mhBacked) GetSize(ctx context.Context, k cid.Cid) (int, error) {
+	return b.mbs.GetSize(ctx, k.Hash())
+}
+
+func (b *mhBacked) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
+	data, err := b.mbs.Get(ctx, k.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return blocks.NewBlockWithCid(data, k)
+}
+
+// View hands data straight from the underlying store to callback without
+// ever materializing a blocks.Block, since the MHBlockstore already deals
+// in raw bytes.
+func (b *mhBacked) View(ctx context.Context, k cid.Cid, callback func([]byte) error) error {
+	data, err := b.mbs.Get(ctx, k.Hash())
+	if err != nil {
+		return err
+	}
+	return callback(data)
+}
+
+func (b *mhBacked) Put(ctx context.Context, bl blocks.Block) error {
+	return b.mbs.Put(ctx, bl.Cid().Hash(), bl.RawData())
+}
+
+func (b *mhBacked) PutMany(ctx context.Context, bs []blocks.Block) error {
+	blks := make([]MHBlock, len(bs))
+	for i, bl := range bs {
+		blks[i] = MHBlock{Hash: bl.Cid().Hash(), Data: bl.RawData()}
+	}
+	return b.mbs.PutMany(ctx, blks)
+}
+
+// HashOnRead passes through to the underlying MHBlockstore when it
+// supports it (every MHBlockstore built by NewMHBlockstore does).
+func (b *mhBacked) HashOnRead(enabled bool) {
+	if hr, ok := b.mbs.(interface{ HashOnRead(bool) }); ok {
+		hr.HashOnRead(enabled)
+	}
+}
+
+func (b *mhBacked) Close() error {
+	if c, ok := b.mbs.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// AllKeysChan enumerates the store's hashes as raw-codec (CIDv1, codec
+// "raw") CIDs, since the original codec of a stored block is not retained
+// once keyed by multihash.
+func (b *mhBacked) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	ch, err := b.mbs.AllHashesChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan cid.Cid)
+	go func() {
+		defer close(out)
+		for h := range ch {
+			select {
+			case out <- canonicalCid(h):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
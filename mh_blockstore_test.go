@@ -0,0 +1,48 @@
+package blockstore
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+)
+
+// TestMHBackedDedupsStorageByHashButPreservesCallerCodec verifies that two
+// CIDs built from different codecs over the same bytes share a single
+// underlying entry (storage is keyed by multihash), while each caller still
+// gets back a block whose CID matches the codec it asked for.
+func TestMHBackedDedupsStorageByHashButPreservesCallerCodec(t *testing.T) {
+	ctx := context.Background()
+	base := newFakeBlockstore()
+	bs := NewMHBacked(NewMHBlockstore(base))
+
+	data := []byte("shared bytes")
+	hash := mustSum(t, data)
+	rawCid := cid.NewCidV1(cid.Raw, hash)
+	dagCBORCid := cid.NewCidV1(cid.DagCBOR, hash)
+
+	rawBlk, err := blocks.NewBlockWithCid(data, rawCid)
+	if err != nil {
+		t.Fatalf("build raw block: %v", err)
+	}
+	if err := bs.Put(ctx, rawBlk); err != nil {
+		t.Fatalf("put raw: %v", err)
+	}
+
+	// The underlying store only has one entry: it's keyed by multihash.
+	if n := len(base.blocks); n != 1 {
+		t.Fatalf("got %d entries in the underlying store, want 1", n)
+	}
+
+	got, err := bs.Get(ctx, dagCBORCid)
+	if err != nil {
+		t.Fatalf("get via dag-cbor codec: %v", err)
+	}
+	if !got.Cid().Equals(dagCBORCid) {
+		t.Errorf("got cid %s, want %s", got.Cid(), dagCBORCid)
+	}
+	if string(got.RawData()) != string(data) {
+		t.Errorf("got bytes %q, want %q", got.RawData(), data)
+	}
+}
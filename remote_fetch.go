@@ -0,0 +1,226 @@
+package blockstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	"golang.org/x/sync/singleflight"
+)
+
+// RemoteFetcher fetches the raw bytes for a CID from an out-of-band source
+// when the CID is not found in the local store. Implementations should
+// return an error wrapping ipld.ErrNotFound when the remote confirms the
+// CID doesn't exist, so callers can tell that apart from a transient
+// failure.
+type RemoteFetcher interface {
+	Fetch(ctx context.Context, c cid.Cid) ([]byte, error)
+}
+
+// FetcherOptions controls how a remoteFetchBlockstore queries its
+// RemoteFetchers.
+type FetcherOptions struct {
+	// Timeout bounds each individual fetcher call; zero means no
+	// per-fetcher timeout beyond ctx's own deadline.
+	Timeout time.Duration
+	// NegativeCacheTTL is how long a confirmed miss (every fetcher
+	// returned not-found) is remembered before the fetchers are tried
+	// again for the same CID; zero disables negative caching.
+	NegativeCacheTTL time.Duration
+	// ProbeRemoteOnHas, when true, lets Has and GetSize fall through to a
+	// full remote fetch (and local write-back) on a local miss, the same
+	// as Get does. It defaults to false: Has/GetSize are presence/size
+	// checks and stay local-only and cheap, matching idstore's behavior
+	// before the remote-fetch fallback existed; callers that want a Has
+	// check to also confirm remote presence must opt in explicitly.
+	ProbeRemoteOnHas bool
+}
+
+// remoteFetchBlockstore wraps a Blockstore with an ordered list of
+// RemoteFetchers that are tried in turn on a local miss. Concurrent misses
+// for the same CID are coalesced with a singleflight group so a burst of
+// callers wanting the same missing CID only triggers one round of fetcher
+// calls.
+type remoteFetchBlockstore struct {
+	bs       Blockstore
+	fetchers []RemoteFetcher
+	opts     FetcherOptions
+
+	sf    singleflight.Group
+	negMu sync.Mutex
+	neg   map[cid.Cid]time.Time
+}
+
+var _ Blockstore = (*remoteFetchBlockstore)(nil)
+
+// NewRemoteFetchBlockstore wraps bs so that a miss falls through fetchers,
+// in order, before giving up. A successful fetch is written back to bs.
+func NewRemoteFetchBlockstore(bs Blockstore, opts FetcherOptions, fetchers ...RemoteFetcher) Blockstore {
+	return &remoteFetchBlockstore{
+		bs:       bs,
+		fetchers: fetchers,
+		opts:     opts,
+		neg:      make(map[cid.Cid]time.Time),
+	}
+}
+
+func (b *remoteFetchBlockstore) DeleteBlock(ctx context.Context, k cid.Cid) error {
+	return b.bs.DeleteBlock(ctx, k)
+}
+
+func (b *remoteFetchBlockstore) Has(ctx context.Context, k cid.Cid) (bool, error) {
+	ok, err := b.bs.Has(ctx, k)
+	if err != nil || ok || !b.opts.ProbeRemoteOnHas {
+		return ok, err
+	}
+	_, err = b.Get(ctx, k)
+	if err != nil {
+		if errors.Is(err, ipld.ErrNotFound{}) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *remoteFetchBlockstore) GetSize(ctx context.Context, k cid.Cid) (int, error) {
+	sz, err := b.bs.GetSize(ctx, k)
+	if err == nil {
+		return sz, nil
+	}
+	if !errors.Is(err, ipld.ErrNotFound{}) || !b.opts.ProbeRemoteOnHas {
+		return 0, err
+	}
+	blk, err := b.Get(ctx, k)
+	if err != nil {
+		return 0, err
+	}
+	return len(blk.RawData()), nil
+}
+
+func (b *remoteFetchBlockstore) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
+	blk, err := b.bs.Get(ctx, k)
+	if err == nil {
+		return blk, nil
+	}
+	if !errors.Is(err, ipld.ErrNotFound{}) {
+		return nil, err
+	}
+	if len(b.fetchers) == 0 {
+		return nil, ipld.ErrNotFound{}
+	}
+	if b.isNegativelyCached(k) {
+		return nil, ipld.ErrNotFound{}
+	}
+
+	v, err, _ := b.sf.Do(k.KeyString(), func() (interface{}, error) {
+		return b.fetch(ctx, k)
+	})
+	if err != nil {
+		if !errors.Is(err, ipld.ErrNotFound{}) {
+			// A transient failure (timeout, connection error, ...) isn't
+			// a confirmed absence: don't poison the negative cache with
+			// it, and let the caller see the real error.
+			logger.Warn("remote fetch: fetcher error, cid[", k.String(), "] : ", err.Error())
+			return nil, err
+		}
+		b.cacheNegative(k)
+		logger.Warn("remote fetch: all fetchers missed, cid[", k.String(), "] : ", err.Error())
+		return nil, ipld.ErrNotFound{}
+	}
+
+	blk, err = blocks.NewBlockWithCid(v.([]byte), k)
+	if err != nil {
+		logger.Warn("remote fetch: new block with cid fail : ", err.Error())
+		return nil, ipld.ErrNotFound{}
+	}
+	if err := b.bs.Put(ctx, blk); err != nil {
+		logger.Warn("remote fetch: put to local store fail : ", err.Error())
+	}
+	logger.Debugf("got block from remote fetcher, cid[%s]", k.String())
+	return blk, nil
+}
+
+// fetch tries every fetcher in order, returning the first success. If none
+// succeed, it returns ipld.ErrNotFound only when every fetcher reported
+// not-found; a fetcher error that isn't a confirmed not-found (timeout,
+// connection failure, ...) takes precedence, since it means the CID's
+// absence was never actually confirmed.
+func (b *remoteFetchBlockstore) fetch(ctx context.Context, k cid.Cid) (interface{}, error) {
+	var hardErr error
+	for _, f := range b.fetchers {
+		fctx := ctx
+		var cancel context.CancelFunc
+		if b.opts.Timeout > 0 {
+			fctx, cancel = context.WithTimeout(ctx, b.opts.Timeout)
+		}
+		data, err := f.Fetch(fctx, k)
+		if cancel != nil {
+			cancel()
+		}
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, ipld.ErrNotFound{}) && hardErr == nil {
+			hardErr = err
+		}
+	}
+	if hardErr != nil {
+		return nil, hardErr
+	}
+	return nil, ipld.ErrNotFound{}
+}
+
+func (b *remoteFetchBlockstore) isNegativelyCached(k cid.Cid) bool {
+	if b.opts.NegativeCacheTTL <= 0 {
+		return false
+	}
+	b.negMu.Lock()
+	defer b.negMu.Unlock()
+	t, ok := b.neg[k]
+	if !ok {
+		return false
+	}
+	if time.Since(t) > b.opts.NegativeCacheTTL {
+		delete(b.neg, k)
+		return false
+	}
+	return true
+}
+
+func (b *remoteFetchBlockstore) cacheNegative(k cid.Cid) {
+	if b.opts.NegativeCacheTTL <= 0 {
+		return
+	}
+	b.negMu.Lock()
+	b.neg[k] = time.Now()
+	b.negMu.Unlock()
+}
+
+func (b *remoteFetchBlockstore) Put(ctx context.Context, bl blocks.Block) error {
+	return b.bs.Put(ctx, bl)
+}
+
+func (b *remoteFetchBlockstore) PutMany(ctx context.Context, bs []blocks.Block) error {
+	return b.bs.PutMany(ctx, bs)
+}
+
+func (b *remoteFetchBlockstore) HashOnRead(enabled bool) {
+	b.bs.HashOnRead(enabled)
+}
+
+func (b *remoteFetchBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	return b.bs.AllKeysChan(ctx)
+}
+
+func (b *remoteFetchBlockstore) Close() error {
+	if c, ok := b.bs.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package blockstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// countingFetcher records how many times Fetch was called and returns a
+// canned response.
+type countingFetcher struct {
+	calls int32
+	data  []byte
+	err   error
+}
+
+func (f *countingFetcher) Fetch(ctx context.Context, c cid.Cid) ([]byte, error) {
+	atomic.AddInt32(&f.calls, 1)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.data, nil
+}
+
+func TestRemoteFetchCoalescesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	bs := newFakeBlockstore()
+	f := &countingFetcher{data: []byte("hello")}
+	rf := NewRemoteFetchBlockstore(bs, FetcherOptions{}, f)
+
+	c := cid.NewCidV1(cid.Raw, mustSum(t, []byte("hello")))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := rf.Get(ctx, c); err != nil {
+				t.Errorf("get: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&f.calls); got != 1 {
+		t.Errorf("expected exactly 1 fetcher call for concurrent misses on the same CID, got %d", got)
+	}
+}
+
+func TestRemoteFetchTransientErrorNotCachedNegative(t *testing.T) {
+	ctx := context.Background()
+	bs := newFakeBlockstore()
+	f := &countingFetcher{err: errors.New("connection reset")}
+	rf := NewRemoteFetchBlockstore(bs, FetcherOptions{NegativeCacheTTL: time.Hour}, f)
+
+	c := cid.NewCidV1(cid.Raw, mustSum(t, []byte("flaky")))
+
+	if _, err := rf.Get(ctx, c); err == nil || errors.Is(err, ipld.ErrNotFound{}) {
+		t.Fatalf("expected transient error to propagate, not ErrNotFound, got %v", err)
+	}
+
+	// A second attempt, now that the fetcher succeeds, must not be blocked
+	// by a bogus negative-cache entry from the earlier transient failure.
+	f.err = nil
+	f.data = []byte("recovered")
+	blk, err := rf.Get(ctx, c)
+	if err != nil {
+		t.Fatalf("expected recovery after transient error, got %v", err)
+	}
+	if string(blk.RawData()) != "recovered" {
+		t.Errorf("got %q, want %q", blk.RawData(), "recovered")
+	}
+}
+
+func TestRemoteFetchConfirmedMissIsCachedNegative(t *testing.T) {
+	ctx := context.Background()
+	bs := newFakeBlockstore()
+	f := &countingFetcher{err: ipld.ErrNotFound{}}
+	rf := NewRemoteFetchBlockstore(bs, FetcherOptions{NegativeCacheTTL: time.Hour}, f)
+
+	c := cid.NewCidV1(cid.Raw, mustSum(t, []byte("absent")))
+
+	if _, err := rf.Get(ctx, c); !errors.Is(err, ipld.ErrNotFound{}) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// Make the fetcher able to succeed now; the negative cache should
+	// still short-circuit the call.
+	f.err = nil
+	f.data = []byte("should not be reached")
+	if _, err := rf.Get(ctx, c); !errors.Is(err, ipld.ErrNotFound{}) {
+		t.Fatalf("expected negative cache hit, got %v", err)
+	}
+	if got := atomic.LoadInt32(&f.calls); got != 1 {
+		t.Errorf("expected fetcher not to be called again while negatively cached, got %d calls", got)
+	}
+}
+
+func TestRemoteFetchHasStaysLocalByDefault(t *testing.T) {
+	ctx := context.Background()
+	bs := newFakeBlockstore()
+	f := &countingFetcher{data: []byte("remote")}
+	rf := NewRemoteFetchBlockstore(bs, FetcherOptions{}, f)
+
+	c := cid.NewCidV1(cid.Raw, mustSum(t, []byte("remote")))
+
+	ok, err := rf.Has(ctx, c)
+	if err != nil {
+		t.Fatalf("has: %v", err)
+	}
+	if ok {
+		t.Errorf("expected Has to report false for a CID that is only remote")
+	}
+	if got := atomic.LoadInt32(&f.calls); got != 0 {
+		t.Errorf("expected Has to stay local-only by default, fetcher was called %d times", got)
+	}
+}
+
+func mustSum(t *testing.T, data []byte) []byte {
+	t.Helper()
+	h, err := mh.Sum(data, mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatalf("sum: %v", err)
+	}
+	return h
+}
@@ -0,0 +1,85 @@
+package blockstore
+
+import (
+	"context"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// fakeBlockstore is a minimal in-memory Blockstore for tests. Entries are
+// keyed by multihash, so a block stored under one CID is found under any
+// other CID with the same hash, and Get returns it under the CID asked for.
+type fakeBlockstore struct {
+	mu     sync.Mutex
+	blocks map[string]blocks.Block
+}
+
+func newFakeBlockstore() *fakeBlockstore {
+	return &fakeBlockstore{blocks: make(map[string]blocks.Block)}
+}
+
+var _ Blockstore = (*fakeBlockstore)(nil)
+
+func (f *fakeBlockstore) DeleteBlock(ctx context.Context, k cid.Cid) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.blocks, string(k.Hash()))
+	return nil
+}
+
+func (f *fakeBlockstore) Has(ctx context.Context, k cid.Cid) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.blocks[string(k.Hash())]
+	return ok, nil
+}
+
+func (f *fakeBlockstore) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	blk, ok := f.blocks[string(k.Hash())]
+	if !ok {
+		return nil, ipld.ErrNotFound{}
+	}
+	return blocks.NewBlockWithCid(blk.RawData(), k)
+}
+
+func (f *fakeBlockstore) GetSize(ctx context.Context, k cid.Cid) (int, error) {
+	blk, err := f.Get(ctx, k)
+	if err != nil {
+		return -1, err
+	}
+	return len(blk.RawData()), nil
+}
+
+func (f *fakeBlockstore) Put(ctx context.Context, bl blocks.Block) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blocks[string(bl.Cid().Hash())] = bl
+	return nil
+}
+
+func (f *fakeBlockstore) PutMany(ctx context.Context, bs []blocks.Block) error {
+	for _, bl := range bs {
+		if err := f.Put(ctx, bl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeBlockstore) HashOnRead(enabled bool) {}
+
+func (f *fakeBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(chan cid.Cid, len(f.blocks))
+	for _, bl := range f.blocks {
+		out <- bl.Cid()
+	}
+	close(out)
+	return out, nil
+}
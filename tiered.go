@@ -0,0 +1,243 @@
+package blockstore
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// tiered composes a read layer and a write layer into a single Blockstore.
+// Has/Get/GetSize check the write layer first and fall through to the read
+// layer on a miss; Put/PutMany land only in the write layer.
+type tiered struct {
+	r Blockstore
+	w Blockstore
+
+	rv Viewer
+	wv Viewer
+}
+
+var _ Blockstore = (*tiered)(nil)
+var _ Viewer = (*tiered)(nil)
+
+// NewTiered composes r and w into a Blockstore that reads from both (write
+// layer first) and writes only to w. Use this directly when r and w are
+// already the two stores you want; BufferedBlockstore builds on top of it
+// with a default in-memory write layer and a Flush helper.
+func NewTiered(r, w Blockstore) Blockstore {
+	t := &tiered{r: r, w: w}
+	if v, ok := r.(Viewer); ok {
+		t.rv = v
+	}
+	if v, ok := w.(Viewer); ok {
+		t.wv = v
+	}
+	return t
+}
+
+func isNotFound(err error) bool {
+	return err != nil && errors.Is(err, ipld.ErrNotFound{})
+}
+
+func (t *tiered) DeleteBlock(ctx context.Context, k cid.Cid) error {
+	err := t.w.DeleteBlock(ctx, k)
+	if err != nil {
+		return err
+	}
+	return t.r.DeleteBlock(ctx, k)
+}
+
+func (t *tiered) Has(ctx context.Context, k cid.Cid) (bool, error) {
+	ok, err := t.w.Has(ctx, k)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, nil
+	}
+	return t.r.Has(ctx, k)
+}
+
+func (t *tiered) GetSize(ctx context.Context, k cid.Cid) (int, error) {
+	sz, err := t.w.GetSize(ctx, k)
+	if err == nil {
+		return sz, nil
+	}
+	if !isNotFound(err) {
+		return 0, err
+	}
+	return t.r.GetSize(ctx, k)
+}
+
+func (t *tiered) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
+	blk, err := t.w.Get(ctx, k)
+	if err == nil {
+		return blk, nil
+	}
+	if !isNotFound(err) {
+		return nil, err
+	}
+	return t.r.Get(ctx, k)
+}
+
+func (t *tiered) Put(ctx context.Context, bl blocks.Block) error {
+	return t.w.Put(ctx, bl)
+}
+
+func (t *tiered) PutMany(ctx context.Context, bs []blocks.Block) error {
+	return t.w.PutMany(ctx, bs)
+}
+
+func (t *tiered) HashOnRead(enabled bool) {
+	t.r.HashOnRead(enabled)
+	t.w.HashOnRead(enabled)
+}
+
+// View passes through to the underlying layer without copying the block's
+// data, provided
+// both layers support Viewer; otherwise it falls back to Get.
+func (t *tiered) View(ctx context.Context, k cid.Cid, callback func([]byte) error) error {
+	if t.rv == nil || t.wv == nil {
+		blk, err := t.Get(ctx, k)
+		if err != nil {
+			return err
+		}
+		return callback(blk.RawData())
+	}
+	err := t.wv.View(ctx, k, callback)
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) {
+		return err
+	}
+	return t.rv.View(ctx, k, callback)
+}
+
+// AllKeysChan merges the keys of both layers, de-duplicating entries that
+// appear in the write layer.
+func (t *tiered) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	wch, err := t.w.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rch, err := t.r.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan cid.Cid)
+	go func() {
+		defer close(out)
+		seen := make(map[cid.Cid]struct{})
+		for c := range wch {
+			seen[c] = struct{}{}
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for c := range rch {
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (t *tiered) Close() error {
+	var firstErr error
+	if c, ok := t.w.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			firstErr = err
+		}
+	}
+	if c, ok := t.r.(io.Closer); ok {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// BufferedBlockstore is a tiered Blockstore whose write layer is a fast
+// in-memory store and whose read layer is the caller's base store (which
+// may itself fall through to a remote fetch path, e.g. idstore's Titan
+// lookup). Writes accumulate in the write layer until Flush copies them
+// down to the base, letting batched, VM-style workloads avoid hitting the
+// base store on every write.
+type BufferedBlockstore struct {
+	*tiered
+}
+
+var _ Blockstore = (*BufferedBlockstore)(nil)
+
+// NewBuffered wraps base with a fast in-memory write layer.
+func NewBuffered(base Blockstore) *BufferedBlockstore {
+	w := NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	return &BufferedBlockstore{
+		tiered: &tiered{
+			r:  base,
+			w:  w,
+			rv: asViewer(base),
+			wv: asViewer(w),
+		},
+	}
+}
+
+func asViewer(bs Blockstore) Viewer {
+	if v, ok := bs.(Viewer); ok {
+		return v
+	}
+	return nil
+}
+
+// Flush copies every block accumulated in the write layer down to the base
+// (read) layer. The write layer is left untouched; callers that want to
+// release memory should discard the BufferedBlockstore afterwards.
+func (b *BufferedBlockstore) Flush(ctx context.Context) error {
+	ch, err := b.w.AllKeysChan(ctx)
+	if err != nil {
+		return err
+	}
+
+	const batchSize = 256
+	batch := make([]blocks.Block, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := b.r.PutMany(ctx, batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for c := range ch {
+		blk, err := b.w.Get(ctx, c)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, blk)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
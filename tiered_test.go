@@ -0,0 +1,99 @@
+package blockstore
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+)
+
+func TestBufferedBlockstoreReadsWriteLayerBeforeFlush(t *testing.T) {
+	ctx := context.Background()
+	base := newFakeBlockstore()
+	buf := NewBuffered(base)
+
+	blk := blocks.NewBlock([]byte("staged"))
+	if err := buf.Put(ctx, blk); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	got, err := buf.Get(ctx, blk.Cid())
+	if err != nil {
+		t.Fatalf("get before flush: %v", err)
+	}
+	if string(got.RawData()) != "staged" {
+		t.Errorf("got %q, want %q", got.RawData(), "staged")
+	}
+
+	if ok, err := base.Has(ctx, blk.Cid()); err != nil {
+		t.Fatalf("base has: %v", err)
+	} else if ok {
+		t.Errorf("expected base store not to see the block before Flush")
+	}
+}
+
+func TestBufferedBlockstoreFlushCopiesToBase(t *testing.T) {
+	ctx := context.Background()
+	base := newFakeBlockstore()
+	buf := NewBuffered(base)
+
+	blk := blocks.NewBlock([]byte("flush me"))
+	if err := buf.Put(ctx, blk); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	if err := buf.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	got, err := base.Get(ctx, blk.Cid())
+	if err != nil {
+		t.Fatalf("base get after flush: %v", err)
+	}
+	if string(got.RawData()) != "flush me" {
+		t.Errorf("got %q, want %q", got.RawData(), "flush me")
+	}
+}
+
+func TestTieredAllKeysChanDedupsAcrossLayers(t *testing.T) {
+	ctx := context.Background()
+	base := newFakeBlockstore()
+	shared := blocks.NewBlock([]byte("shared"))
+	baseOnly := blocks.NewBlock([]byte("base only"))
+	if err := base.Put(ctx, shared); err != nil {
+		t.Fatalf("put shared into base: %v", err)
+	}
+	if err := base.Put(ctx, baseOnly); err != nil {
+		t.Fatalf("put base-only: %v", err)
+	}
+
+	buf := NewBuffered(base)
+	// A stale copy of shared in the write layer must not produce a
+	// duplicate entry in AllKeysChan.
+	if err := buf.Put(ctx, shared); err != nil {
+		t.Fatalf("put shared into write layer: %v", err)
+	}
+	writeOnly := blocks.NewBlock([]byte("write only"))
+	if err := buf.Put(ctx, writeOnly); err != nil {
+		t.Fatalf("put write-only: %v", err)
+	}
+
+	ch, err := buf.AllKeysChan(ctx)
+	if err != nil {
+		t.Fatalf("allkeyschan: %v", err)
+	}
+	counts := make(map[cid.Cid]int)
+	for c := range ch {
+		counts[c]++
+	}
+
+	for _, want := range []cid.Cid{shared.Cid(), baseOnly.Cid(), writeOnly.Cid()} {
+		if counts[want] != 1 {
+			t.Errorf("cid %s: got %d entries, want exactly 1", want, counts[want])
+		}
+	}
+	if len(counts) != 3 {
+		t.Errorf("got %d distinct keys, want 3: %v", len(counts), counts)
+	}
+}